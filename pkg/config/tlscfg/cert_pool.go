@@ -0,0 +1,92 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadCertPool builds a trust pool from zero or more comma-separated PEM
+// files (paths) plus optional inline PEM data (inlinePEM), concatenating
+// all of their contents before parsing. This lets operators stage a CA
+// rollover by listing both the old and new root in paths, or by supplying
+// the new root inline while the old one is still referenced by path.
+//
+// Every referenced file must exist and be readable; a missing file is
+// always an error. Parsing, however, is best-effort across the combined
+// input: it is only an error if none of the sources yielded a usable
+// certificate. The parsed certificates are also returned so callers (e.g.
+// expiry metrics) don't need to re-decode the pool, which x509.CertPool
+// does not allow enumerating.
+func loadCertPool(paths string, inlinePEM []byte) (*x509.CertPool, []*x509.Certificate, error) {
+	var combined bytes.Buffer
+	for _, path := range splitPaths(paths) {
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load CA %s: %w", path, err)
+		}
+		combined.Write(data)
+		combined.WriteByte('\n')
+	}
+	combined.Write(inlinePEM)
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(combined.Bytes()) {
+		return nil, nil, fmt.Errorf("failed to parse CA %s", paths)
+	}
+	return certPool, parsePEMCertificates(combined.Bytes()), nil
+}
+
+// parsePEMCertificates decodes every "CERTIFICATE" PEM block in data,
+// skipping blocks that fail to parse; it mirrors the best-effort behavior
+// of x509.CertPool.AppendCertsFromPEM.
+func parsePEMCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
+// splitPaths splits a comma-separated list of paths, trimming whitespace
+// and dropping empty entries.
+func splitPaths(paths string) []string {
+	if paths == "" {
+		return nil
+	}
+	var out []string
+	for _, p := range strings.Split(paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
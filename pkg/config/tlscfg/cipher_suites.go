@@ -0,0 +1,47 @@
+// Copyright (c) 2021 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// cipherSuiteMap maps the human-readable cipher suite names accepted on the
+// command line to the corresponding constants from the crypto/tls package.
+var cipherSuiteMap = func() map[string]uint16 {
+	m := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		m[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		m[suite.Name] = suite.ID
+	}
+	return m
+}()
+
+// CipherSuiteNamesToIDs converts a list of cipher suite names, as defined by
+// the crypto/tls package, into their corresponding IDs.
+func CipherSuiteNamesToIDs(cipherSuiteNames []string) ([]uint16, error) {
+	var cipherSuiteIds []uint16
+	for _, name := range cipherSuiteNames {
+		id, ok := cipherSuiteMap[name]
+		if !ok {
+			return nil, fmt.Errorf("cipher suite %s not supported or doesn't exist", name)
+		}
+		cipherSuiteIds = append(cipherSuiteIds, id)
+	}
+	return cipherSuiteIds, nil
+}
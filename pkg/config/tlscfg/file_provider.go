@@ -0,0 +1,302 @@
+// Copyright (c) 2019 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileProvider is the CertificateProvider backed by the CAPath,
+// ClientCAPath, CertPath and KeyPath files on Options; it is the only
+// provider selected by Options.Source == "" or "file", which is also the
+// default.
+type fileProvider struct {
+	opts    *Options
+	logger  *zap.Logger
+	metrics *tlsMetrics
+
+	reloader *certReloader
+	changeCh chan struct{}
+
+	// reloadMu serializes reload(), since ReloadInterval and ReloadOnChange
+	// can both be set and would otherwise race on the fingerprint fields
+	// below as well as on the "did anything change" decision.
+	reloadMu   sync.Mutex
+	caFP       string
+	clientCAFP string
+	certFP     string
+
+	mu           sync.RWMutex
+	certPool     *x509.CertPool
+	clientCAPool *x509.CertPool
+	cert         *tls.Certificate
+}
+
+// newFileProvider builds a fileProvider. metrics, shared with the rest of
+// Options.Config, may be nil if Options.Metrics was never set.
+func newFileProvider(opts *Options, logger *zap.Logger, metrics *tlsMetrics) (*fileProvider, error) {
+	certPool, caCerts, err := opts.loadCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA CertPool: %w", err)
+	}
+
+	cert, err := opts.loadCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAPool, clientCACerts, err := opts.loadClientCertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &fileProvider{
+		opts:         opts,
+		logger:       logger,
+		metrics:      metrics,
+		certPool:     certPool,
+		clientCAPool: clientCAPool,
+		cert:         cert,
+		// changeCh is small and non-blocking: Subscribe is a best-effort
+		// notification, not a queue callers must fully drain.
+		changeCh: make(chan struct{}, 1),
+	}
+
+	// Seed the fingerprints from the files just loaded above, so the first
+	// reload tick/event doesn't treat unchanged files as a rotation. A
+	// failure here just means the first reload will re-read and re-parse,
+	// same as if nothing had been cached.
+	p.caFP, _ = fileFingerprint(splitPaths(opts.CAPath), opts.CAPEM)
+	p.clientCAFP, _ = fileFingerprint(splitPaths(opts.ClientCAPath), opts.ClientCAPEM)
+	if opts.CertPath != "" && opts.KeyPath != "" {
+		p.certFP, _ = fileFingerprint([]string{opts.CertPath, opts.KeyPath}, nil)
+	}
+
+	p.metrics.observePool(roleCA, caCerts)
+	p.metrics.observePool(roleClientCA, clientCACerts)
+	p.metrics.observeLeaf(roleServer, cert)
+	p.metrics.observeLeaf(roleClient, cert)
+
+	if opts.ReloadInterval > 0 || opts.ReloadOnChange {
+		p.startReloader()
+	}
+
+	return p, nil
+}
+
+func (p *fileProvider) GetServerCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cert, nil
+}
+
+func (p *fileProvider) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return p.cert, nil
+}
+
+func (p *fileProvider) GetRoots() (*x509.CertPool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.certPool, nil
+}
+
+func (p *fileProvider) GetClientCAs() (*x509.CertPool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.clientCAPool, nil
+}
+
+func (p *fileProvider) Subscribe() <-chan struct{} {
+	return p.changeCh
+}
+
+func (p *fileProvider) notifyChanged() {
+	select {
+	case p.changeCh <- struct{}{}:
+	default:
+		// a notification is already pending; callers re-fetch current
+		// state rather than replaying every intermediate change.
+	}
+}
+
+// startReloader launches the background goroutine(s) that keep p's
+// certificates and CA pools fresh: a ticker-based poll loop when
+// ReloadInterval is set, and an fsnotify-based watch when ReloadOnChange is
+// set. Both can run at once. It is only called by newFileProvider.
+func (p *fileProvider) startReloader() {
+	opts := p.opts
+	r := &certReloader{closeCh: make(chan struct{})}
+	p.reloader = r
+
+	if opts.ReloadInterval > 0 {
+		ticker := time.NewTicker(opts.ReloadInterval)
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					opts.notifyReload(p.reload())
+				case <-r.closeCh:
+					return
+				}
+			}
+		}()
+	}
+
+	if opts.ReloadOnChange {
+		p.startFileWatcher(r)
+	}
+}
+
+// reload checks the CA, client-CA, cert and key files for changes, using a
+// content fingerprint to decide whether each component needs to be
+// re-read and re-parsed at all. Only a component whose fingerprint changed
+// is actually reloaded and swapped in; parse failures for such a component
+// are logged and the previously loaded, known-good material keeps serving.
+// It returns the combined error of any failed component, or nil if
+// everything reloaded cleanly (or there was nothing to reload).
+func (p *fileProvider) reload() error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	opts := p.opts
+	var errs []error
+	changed := false
+
+	if fp, err := fileFingerprint(splitPaths(opts.CAPath), opts.CAPEM); err != nil || fp != p.caFP {
+		if certPool, caCerts, err := opts.loadCertPool(); err != nil {
+			p.logger.Error("failed to reload CA certificate(s), keeping previous CA pool", zap.Error(err))
+			errs = append(errs, err)
+		} else {
+			p.mu.Lock()
+			p.certPool = certPool
+			p.mu.Unlock()
+			p.caFP = fp
+			p.metrics.observePool(roleCA, caCerts)
+			p.logger.Info("reloaded CA certificate(s)")
+			changed = true
+		}
+	}
+
+	if opts.ClientCAPath != "" || len(opts.ClientCAPEM) != 0 {
+		if fp, err := fileFingerprint(splitPaths(opts.ClientCAPath), opts.ClientCAPEM); err != nil || fp != p.clientCAFP {
+			if clientCAPool, clientCACerts, err := opts.loadClientCertPool(); err != nil {
+				p.logger.Error("failed to reload client CA certificate(s), keeping previous client CA pool", zap.Error(err))
+				errs = append(errs, err)
+			} else {
+				p.mu.Lock()
+				p.clientCAPool = clientCAPool
+				p.mu.Unlock()
+				p.clientCAFP = fp
+				p.metrics.observePool(roleClientCA, clientCACerts)
+				p.logger.Info("reloaded client CA certificate(s)")
+				changed = true
+			}
+		}
+	}
+
+	if opts.CertPath != "" && opts.KeyPath != "" {
+		if fp, err := fileFingerprint([]string{opts.CertPath, opts.KeyPath}, nil); err != nil || fp != p.certFP {
+			if cert, err := opts.loadCertificate(); err != nil {
+				p.logger.Error("failed to reload TLS cert and key, keeping previous certificate", zap.Error(err))
+				errs = append(errs, err)
+			} else {
+				p.mu.Lock()
+				p.cert = cert
+				p.mu.Unlock()
+				p.certFP = fp
+				p.metrics.observeLeaf(roleServer, cert)
+				p.metrics.observeLeaf(roleClient, cert)
+				p.logger.Info("reloaded TLS cert and key")
+				changed = true
+			}
+		}
+	}
+
+	if !changed && len(errs) == 0 {
+		return nil
+	}
+
+	err := errors.Join(errs...)
+	p.metrics.recordReload(err)
+	if changed {
+		p.notifyChanged()
+	}
+	return err
+}
+
+func (p *Options) loadCertificate() (*tls.Certificate, error) {
+	if p.CertPath == "" && p.KeyPath == "" {
+		return nil, nil
+	}
+	if p.CertPath == "" || p.KeyPath == "" {
+		return nil, fmt.Errorf("for client auth via TLS, both client certificate and key must be supplied")
+	}
+	cert, err := tls.LoadX509KeyPair(filepath.Clean(p.CertPath), filepath.Clean(p.KeyPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server TLS cert and key: %w", err)
+	}
+	return &cert, nil
+}
+
+func (p *Options) loadCertPool() (*x509.CertPool, []*x509.Certificate, error) {
+	if len(p.CAPath) == 0 && len(p.CAPEM) == 0 {
+		certPool, err := systemCertPool()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load SystemCertPool: %w", err)
+		}
+		return certPool, nil, nil
+	}
+	return loadCertPool(p.CAPath, p.CAPEM)
+}
+
+func (p *Options) loadClientCertPool() (*x509.CertPool, []*x509.Certificate, error) {
+	if len(p.ClientCAPath) == 0 && len(p.ClientCAPEM) == 0 {
+		return nil, nil, nil
+	}
+	return loadCertPool(p.ClientCAPath, p.ClientCAPEM)
+}
+
+// Close stops the background certificate reload goroutine, if one was
+// started.
+func (p *fileProvider) Close() error {
+	r := p.reloader
+	if r == nil {
+		return nil
+	}
+	select {
+	case <-r.closeCh:
+		// already closed
+	default:
+		close(r.closeCh)
+	}
+	r.wg.Wait()
+	return nil
+}
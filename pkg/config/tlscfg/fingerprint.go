@@ -0,0 +1,45 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fileFingerprint hashes the contents of paths (in order) plus inline (if
+// any) into a single SHA-256 digest. reload() compares this against the
+// last-seen fingerprint so it only pays for a full parse (PEM decoding,
+// X.509 parsing, tls.X509KeyPair) when the content has actually changed,
+// instead of on every poll tick or filesystem event. It's keyed on content
+// rather than mtime so that touching a file without changing it, or a
+// filesystem with coarse mtime resolution, can't mask a real change or
+// manufacture a false one.
+func fileFingerprint(paths []string, inline []byte) (string, error) {
+	h := sha256.New()
+	for _, path := range paths {
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	h.Write(inline)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2019 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	tlsPrefix         = ".tls"
+	tlsEnabled        = tlsPrefix + ".enabled"
+	tlsCA             = tlsPrefix + ".ca"
+	tlsCert           = tlsPrefix + ".cert"
+	tlsKey            = tlsPrefix + ".key"
+	tlsClientCA       = tlsPrefix + ".client-ca"
+	tlsCipherSuites   = tlsPrefix + ".cipher-suites"
+	tlsMinVersion     = tlsPrefix + ".min-version"
+	tlsMaxVersion     = tlsPrefix + ".max-version"
+	tlsReloadInterval = tlsPrefix + ".reload-interval"
+	tlsReloadOnChange = tlsPrefix + ".reload-on-change"
+	tlsSource         = tlsPrefix + ".source"
+)
+
+// AddFlags adds flags for tls.Options, prefixed with the given string.
+func AddFlags(flags *flag.FlagSet, prefix string) {
+	flags.Bool(prefix+tlsEnabled, false, "Enable TLS")
+	flags.String(prefix+tlsCA, "", "Comma-separated list of paths to TLS CA (Certification Authority) files used to verify the remote server(s) (by default will use the system truststore)")
+	flags.String(prefix+tlsCert, "", "Path to a TLS Certificate file, used to identify this process to the remote server(s)")
+	flags.String(prefix+tlsKey, "", "Path to a TLS Private Key file, used to identify this process to the remote server(s)")
+	flags.String(prefix+tlsClientCA, "", "Comma-separated list of paths to TLS CA (Certification Authority) files used to verify certificates presented by clients (if unset, all clients are permitted)")
+	flags.String(prefix+tlsCipherSuites, "", "Comma-separated list of cipher suites for the server, values are from tls package constants (https://golang.org/pkg/crypto/tls/#pkg-constants)")
+	flags.String(prefix+tlsMinVersion, "", "Minimum TLS version supported (Possible values: 1.0, 1.1, 1.2, 1.3)")
+	flags.String(prefix+tlsMaxVersion, "", "Maximum TLS version supported (Possible values: 1.0, 1.1, 1.2, 1.3)")
+	flags.Duration(prefix+tlsReloadInterval, 0, "The duration after which the certificate(s) will be reloaded (0s means will not be reloaded)")
+	flags.Bool(prefix+tlsReloadOnChange, false, "Whether the certificate(s) should be reloaded as soon as a change is detected on disk, in addition to reload-interval")
+	flags.String(prefix+tlsSource, "", "Source of the TLS certificate material: file (default), spiffe, or vault")
+}
+
+// InitFromViper creates tls.Options populated with values retrieved from Viper.
+func (p *Options) InitFromViper(prefix string, v *viper.Viper) {
+	p.Enabled = v.GetBool(prefix + tlsEnabled)
+	p.CAPath = v.GetString(prefix + tlsCA)
+	p.CertPath = v.GetString(prefix + tlsCert)
+	p.KeyPath = v.GetString(prefix + tlsKey)
+	p.ClientCAPath = v.GetString(prefix + tlsClientCA)
+	if suites := v.GetString(prefix + tlsCipherSuites); suites != "" {
+		for _, s := range strings.Split(suites, ",") {
+			p.CipherSuites = append(p.CipherSuites, strings.TrimSpace(s))
+		}
+	}
+	p.MinVersion = v.GetString(prefix + tlsMinVersion)
+	p.MaxVersion = v.GetString(prefix + tlsMaxVersion)
+	p.ReloadInterval = v.GetDuration(prefix + tlsReloadInterval)
+	p.ReloadOnChange = v.GetBool(prefix + tlsReloadOnChange)
+	p.Source = v.GetString(prefix + tlsSource)
+}
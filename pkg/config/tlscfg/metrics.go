@@ -0,0 +1,125 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"github.com/jaegertracing/jaeger/pkg/metrics"
+)
+
+// certRole identifies which of the certificates/pools loaded by Options a
+// metric applies to.
+type certRole string
+
+const (
+	roleServer   certRole = "server"
+	roleClient   certRole = "client"
+	roleCA       certRole = "ca"
+	roleClientCA certRole = "client-ca"
+)
+
+// timeNow is indirected so tests can inject a fake clock when asserting
+// expiry gauge values for a near-expiry certificate.
+var timeNow = time.Now
+
+// tlsMetrics holds the Prometheus-style metrics published for a single
+// Options.Config call.
+type tlsMetrics struct {
+	reloadSuccesses metrics.Counter
+	reloadFailures  metrics.Counter
+
+	// handshakesSucceeded only ever counts successes: it is driven from
+	// VerifyConnection, which the standard library calls only after the
+	// peer certificate has passed verification (by VerifyPeerCertificate or
+	// the server's client-certificate check) or verification was skipped
+	// via SkipHostVerify, so there is no reachable failure case to count
+	// here. See recordHandshake.
+	handshakesSucceeded metrics.Counter
+
+	certExpiry map[certRole]metrics.Gauge
+}
+
+func newTLSMetrics(factory metrics.Factory) *tlsMetrics {
+	factory = factory.Namespace(metrics.NSOptions{Name: "tls"})
+	m := &tlsMetrics{
+		reloadSuccesses:     factory.Counter(metrics.Options{Name: "reload_total", Tags: map[string]string{"result": "success"}}),
+		reloadFailures:      factory.Counter(metrics.Options{Name: "reload_failures_total"}),
+		handshakesSucceeded: factory.Counter(metrics.Options{Name: "handshakes_total", Tags: map[string]string{"result": "success"}}),
+		certExpiry:          make(map[certRole]metrics.Gauge),
+	}
+	for _, role := range []certRole{roleServer, roleClient, roleCA, roleClientCA} {
+		m.certExpiry[role] = factory.Gauge(metrics.Options{
+			Name: "cert_expiry_seconds",
+			Tags: map[string]string{"role": string(role)},
+		})
+	}
+	return m
+}
+
+// recordReload updates the reload success/failure counters.
+func (m *tlsMetrics) recordReload(err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.reloadFailures.Inc(1)
+		return
+	}
+	m.reloadSuccesses.Inc(1)
+}
+
+// recordHandshake counts a handshake; installed as
+// tls.Config.VerifyConnection, which the standard library only invokes once
+// the peer certificate has passed verification (or verification was
+// skipped), so reaching here always counts as a success. It never itself
+// rejects a connection.
+func (m *tlsMetrics) recordHandshake(tls.ConnectionState) error {
+	m.handshakesSucceeded.Inc(1)
+	return nil
+}
+
+// observeLeaf updates the expiry gauge for role from cert's leaf, if any.
+func (m *tlsMetrics) observeLeaf(role certRole, cert *tls.Certificate) {
+	if m == nil || cert == nil || len(cert.Certificate) == 0 {
+		return
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return
+		}
+	}
+	m.certExpiry[role].Update(int64(leaf.NotAfter.Sub(timeNow()).Seconds()))
+}
+
+// observePool updates the expiry gauge for role to the soonest NotAfter
+// among the certificates in pool.
+func (m *tlsMetrics) observePool(role certRole, certs []*x509.Certificate) {
+	if m == nil || len(certs) == 0 {
+		return
+	}
+	soonest := certs[0].NotAfter
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(soonest) {
+			soonest = c.NotAfter
+		}
+	}
+	m.certExpiry[role].Update(int64(soonest.Sub(timeNow()).Seconds()))
+}
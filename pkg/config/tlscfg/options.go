@@ -0,0 +1,343 @@
+// Copyright (c) 2019 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/metrics"
+)
+
+// systemCertPool is indirected for testing.
+var systemCertPool = x509.SystemCertPool
+
+// SourceFile, SourceSPIFFE and SourceVault are the recognized values for
+// Options.Source. SourceFile is the default.
+const (
+	SourceFile   = "file"
+	SourceSPIFFE = "spiffe"
+	SourceVault  = "vault"
+)
+
+// Options describes the configuration properties for TLS Connections.
+type Options struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertPath string `mapstructure:"cert"`
+	KeyPath  string `mapstructure:"key"`
+	// CAPath is one or more PEM-encoded CA certificate files used to
+	// verify the remote server(s). Multiple files can be supplied as a
+	// comma-separated list, e.g. to trust both an old and a new root
+	// during a CA rollover; their certificates are concatenated into a
+	// single trust pool. If empty (and CAPEM is also empty), the system
+	// truststore is used.
+	CAPath string `mapstructure:"ca"`
+	// CAPEM holds inline, PEM-encoded CA certificate data in addition to
+	// (or instead of) CAPath; both sources are merged into the same pool.
+	CAPEM []byte `mapstructure:"-"`
+	// ClientCAPath is one or more PEM-encoded CA certificate files, comma
+	// separated, used to verify certificates presented by clients. See
+	// CAPath for the multi-file and rollover semantics.
+	ClientCAPath string `mapstructure:"client_ca"`
+	// ClientCAPEM holds inline, PEM-encoded client CA certificate data in
+	// addition to (or instead of) ClientCAPath.
+	ClientCAPEM    []byte   `mapstructure:"-"`
+	CipherSuites   []string `mapstructure:"cipher_suites"`
+	MinVersion     string   `mapstructure:"min_version"`
+	MaxVersion     string   `mapstructure:"max_version"`
+	ServerName     string   `mapstructure:"-"`
+	SkipHostVerify bool     `mapstructure:"-"`
+	// ReloadInterval is the duration after which the certificates, CA and
+	// key files are reloaded from disk. If zero, no polling-based reloading
+	// is performed and the certificates loaded by Config are used for the
+	// lifetime of the returned *tls.Config, unless ReloadOnChange is set.
+	// Only honored by the file source.
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+	// ReloadOnChange watches the directories holding CAPath, ClientCAPath,
+	// CertPath and KeyPath for filesystem events (via fsnotify) and
+	// triggers an immediate reload instead of, or in addition to, the
+	// ReloadInterval polling loop. Only honored by the file source.
+	ReloadOnChange bool `mapstructure:"reload_on_change"`
+	// OnReload, if set, is invoked after every reload attempt started by
+	// ReloadInterval or ReloadOnChange, with err nil on success or when
+	// none of the files had changed, so that callers can hook reload
+	// outcomes into their own metrics or logging.
+	OnReload func(err error) `mapstructure:"-"`
+	// Metrics, if set, publishes counters for cert reloads, gauges for the
+	// seconds left until the NotAfter of the current certificate and of
+	// the soonest-expiring CA in each pool, and a counter of successful
+	// handshakes under the "tls" namespace. Reload and expiry metrics are
+	// only published by the file source.
+	Metrics metrics.Factory `mapstructure:"-"`
+
+	// Source selects the CertificateProvider implementation: SourceFile
+	// (the default, used when empty), SourceSPIFFE or SourceVault. See
+	// pkg/config/tlscfg/providers for the SPIFFE and Vault backends.
+	Source string `mapstructure:"source"`
+	// SPIFFE holds the sub-options for Source == SourceSPIFFE.
+	SPIFFE SPIFFEOptions `mapstructure:"spiffe"`
+	// Vault holds the sub-options for Source == SourceVault.
+	Vault VaultOptions `mapstructure:"vault"`
+
+	// providerVal holds the *providerHolder built by Config, so Close can
+	// release it. It's an atomic.Value rather than a plain field because
+	// Config/Close may be invoked concurrently on the same Options (see
+	// TestConcurrentConfigAccess), and an atomic.Value, unlike a
+	// sync.Mutex, can live directly on Options without go vet flagging
+	// every place Options itself is copied by value (e.g. table tests).
+	providerVal atomic.Value
+}
+
+// providerHolder boxes a CertificateProvider so Options.providerVal always
+// stores values of the same concrete type, as atomic.Value requires.
+type providerHolder struct {
+	provider CertificateProvider
+}
+
+// SPIFFEOptions configures the SPIFFE Workload API backed provider.
+type SPIFFEOptions struct {
+	// WorkloadAPIAddr is the address of the SPIFFE Workload API, e.g.
+	// "unix:///run/spire/sockets/agent.sock". If empty, the go-spiffe
+	// default (the SPIFFE_ENDPOINT_SOCKET environment variable) is used.
+	WorkloadAPIAddr string `mapstructure:"workload_api_addr"`
+}
+
+// VaultOptions configures the HashiCorp Vault PKI secrets engine backed
+// provider.
+type VaultOptions struct {
+	// Addr is the Vault server address, e.g. "https://vault:8200".
+	Addr string `mapstructure:"addr"`
+	// Token authenticates to Vault; in production this is typically
+	// supplied out of band (e.g. via VAULT_TOKEN) rather than this field.
+	Token string `mapstructure:"-"`
+	// PKIMountPath is the mount path of the PKI secrets engine, e.g. "pki".
+	PKIMountPath string `mapstructure:"pki_mount_path"`
+	// Role is the PKI role used to issue certificates.
+	Role string `mapstructure:"role"`
+	// CommonName is the CN requested for issued certificates.
+	CommonName string `mapstructure:"common_name"`
+	// TTL is the requested certificate lifetime; Vault may cap it.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// certReloader owns the lifecycle of the background goroutine(s) started
+// by a CertificateProvider that supports interval polling and/or
+// filesystem-event based reloading (currently only fileProvider).
+type certReloader struct {
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Config builds a *tls.Config backed by the CertificateProvider selected by
+// p.Source (SourceFile if unset). The returned config's certificate and CA
+// pool fields are resolved dynamically from the provider on every
+// handshake, so a provider that rotates its material in the background
+// (file reload, SPIFFE SVID rotation, Vault re-issuance) takes effect
+// without rebuilding the *tls.Config.
+func (p *Options) Config(logger *zap.Logger) (*tls.Config, error) {
+	var m *tlsMetrics
+	if p.Metrics != nil {
+		m = newTLSMetrics(p.Metrics)
+	}
+
+	provider, err := p.newProvider(logger, m)
+	if err != nil {
+		return nil, err
+	}
+	p.providerVal.Store(&providerHolder{provider: provider})
+
+	minVersionID, err := allowedTLSVersion(p.MinVersion, tls.VersionTLS12)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minimum tls version: %w", err)
+	}
+	maxVersionID, err := allowedTLSVersion(p.MaxVersion, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maximum tls version: %w", err)
+	}
+	if p.MaxVersion != "" && minVersionID > maxVersionID {
+		return nil, fmt.Errorf("minimum tls version can't be greater than maximum tls version")
+	}
+
+	cipherSuiteIDs, err := CipherSuiteNamesToIDs(p.CipherSuites)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cipher suite ids from cipher suite names: %w", err)
+	}
+
+	certPool, err := provider.GetRoots()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA CertPool: %w", err)
+	}
+	clientCAPool, err := provider.GetClientCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth := tls.NoClientCert
+	if clientCAPool != nil {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	var verifyPeer func([][]byte, [][]*x509.Certificate) error
+	if !p.SkipHostVerify {
+		verifyPeer = verifyPeerCertificate(provider, p.ServerName)
+	}
+
+	tlsCfg := &tls.Config{
+		RootCAs:               certPool,
+		ClientCAs:             clientCAPool,
+		GetCertificate:        provider.GetServerCertificate,
+		GetClientCertificate:  provider.GetClientCertificate,
+		GetConfigForClient:    getConfigForClient(provider, p.ServerName, clientAuth),
+		VerifyConnection:      verifyConnection(m),
+		VerifyPeerCertificate: verifyPeer,
+		ClientAuth:            clientAuth,
+		ServerName:            p.ServerName,
+		CipherSuites:          cipherSuiteIDs,
+		MinVersion:            minVersionID,
+		MaxVersion:            maxVersionID,
+		// InsecureSkipVerify disables crypto/tls's own certificate
+		// verification, which reads RootCAs once into the connection and
+		// would otherwise never see a reloaded CA pool when this *tls.Config
+		// is used as a TLS client: GetConfigForClient (used above for the
+		// server role) is only ever consulted by the server-side handshake.
+		// VerifyPeerCertificate re-verifies the chain against the
+		// provider's current roots on every handshake instead, unless
+		// SkipHostVerify asked for no verification at all.
+		InsecureSkipVerify: true, //nolint:gosec
+	}
+	tlsCfg.GetConfigForClient = getConfigForClient(provider, tlsCfg)
+
+	return tlsCfg, nil
+}
+
+// verifyPeerCertificate returns a tls.Config.VerifyPeerCertificate hook that
+// re-implements chain and (if serverName is set) hostname verification
+// against provider.GetRoots(), resolved fresh on every call. It exists so
+// that a CertificateProvider's rotated CA pool is honored by the TLS client
+// role, which crypto/tls never re-resolves per-connection the way it does
+// for servers via GetConfigForClient.
+func verifyPeerCertificate(provider CertificateProvider, serverName string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("no certificate presented by peer")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("failed to parse peer certificate: %w", err)
+			}
+			certs[i] = cert
+		}
+
+		roots, err := provider.GetRoots()
+		if err != nil {
+			return err
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			DNSName:       serverName,
+		}); err != nil {
+			return fmt.Errorf("failed to verify peer certificate: %w", err)
+		}
+		return nil
+	}
+}
+
+// newProvider builds the CertificateProvider selected by p.Source. metrics
+// is shared with the VerifyConnection hook built by Config, so lifecycle
+// (reload), expiry and handshake metrics all come from one *tlsMetrics
+// instance instead of each registering its own copy of the same names.
+func (p *Options) newProvider(logger *zap.Logger, metrics *tlsMetrics) (CertificateProvider, error) {
+	switch p.Source {
+	case "", SourceFile:
+		return newFileProvider(p, logger, metrics)
+	case SourceSPIFFE:
+		return newSPIFFEProvider(p.SPIFFE, logger)
+	case SourceVault:
+		return newVaultProvider(p.Vault, logger)
+	default:
+		return nil, fmt.Errorf("unknown tls source %q", p.Source)
+	}
+}
+
+// getConfigForClient returns a GetConfigForClient closure that resolves the
+// current CA/client-CA pools and certificate from provider at handshake
+// time, since tls.Config.RootCAs/ClientCAs are otherwise read once and
+// cached internally by the standard library.
+func getConfigForClient(provider CertificateProvider, serverName string, clientAuth tls.ClientAuthType) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		roots, err := provider.GetRoots()
+		if err != nil {
+			return nil, err
+		}
+		clientCAs, err := provider.GetClientCAs()
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			RootCAs:              roots,
+			ClientCAs:            clientCAs,
+			ClientAuth:           clientAuth,
+			GetCertificate:       provider.GetServerCertificate,
+			GetClientCertificate: provider.GetClientCertificate,
+			ServerName:           serverName,
+		}, nil
+	}
+}
+
+// verifyConnection returns a tls.Config.VerifyConnection hook that only
+// observes handshake outcomes for m (if non-nil) and never itself rejects a
+// connection.
+func verifyConnection(m *tlsMetrics) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if m == nil {
+			return nil
+		}
+		return m.recordHandshake(cs)
+	}
+}
+
+// notifyReload invokes p.OnReload, if set, with the outcome of a single
+// reload attempt. Only called by providers that support reloading (file).
+func (p *Options) notifyReload(err error) {
+	if p.OnReload != nil {
+		p.OnReload(err)
+	}
+}
+
+// Close releases any background resources held by the provider created by
+// Config (e.g. the file reload goroutine, a SPIFFE workload API stream, a
+// Vault lease renewer). It is safe to call even if Config was never called,
+// and safe to call more than once.
+func (p *Options) Close() error {
+	v, _ := p.providerVal.Load().(*providerHolder)
+	if v == nil {
+		return nil
+	}
+	return v.provider.Close()
+}
@@ -21,11 +21,15 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/metrics/metricstest"
 )
 
 var testCertKeyLocation = "./testdata"
@@ -189,6 +193,137 @@ func TestOptionsToConfig(t *testing.T) {
 	}
 }
 
+func TestLoadCertPoolMultipleFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		paths       string
+		inlinePEM   []byte
+		expectError string
+	}{
+		{
+			name:  "two valid CA files are both trusted",
+			paths: testCertKeyLocation + "/example-CA-cert.pem," + testCertKeyLocation + "/example-CA-cert-2.pem",
+		},
+		{
+			name:  "whitespace around comma-separated paths is trimmed",
+			paths: testCertKeyLocation + "/example-CA-cert.pem, " + testCertKeyLocation + "/example-CA-cert-2.pem",
+		},
+		{
+			name:        "missing file in the list is always an error",
+			paths:       testCertKeyLocation + "/example-CA-cert.pem," + testCertKeyLocation + "/not/valid",
+			expectError: "failed to load CA",
+		},
+		{
+			name:      "inline PEM merges with file-based CAs",
+			paths:     testCertKeyLocation + "/example-CA-cert.pem",
+			inlinePEM: readFile(t, testCertKeyLocation+"/example-CA-cert-2.pem"),
+		},
+		{
+			name:      "inline PEM alone is sufficient",
+			inlinePEM: readFile(t, testCertKeyLocation+"/example-CA-cert.pem"),
+		},
+		{
+			name:        "a garbage file alongside a valid one still yields a usable pool",
+			paths:       testCertKeyLocation + "/example-CA-cert.pem," + testCertKeyLocation + "/bad-CA-cert.txt",
+			expectError: "",
+		},
+		{
+			name:        "only garbage input fails to parse",
+			paths:       testCertKeyLocation + "/bad-CA-cert.txt",
+			expectError: "failed to parse CA",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool, _, err := loadCertPool(test.paths, test.inlinePEM)
+			if test.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectError)
+			} else {
+				require.NoError(t, err)
+				assert.NotNil(t, pool)
+			}
+		})
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Clean(path))
+	require.NoError(t, err)
+	return data
+}
+
+func TestMetricsCertExpiryGauge(t *testing.T) {
+	certPath := testCertKeyLocation + "/near-expiry-cert.pem"
+	keyPath := testCertKeyLocation + "/near-expiry-key.pem"
+
+	leaf, err := tls.LoadX509KeyPair(certPath, keyPath)
+	require.NoError(t, err)
+	parsedLeaf, err := x509.ParseCertificate(leaf.Certificate[0])
+	require.NoError(t, err)
+
+	fakeNow := parsedLeaf.NotAfter.Add(-6 * 24 * time.Hour)
+	savedNow := timeNow
+	timeNow = func() time.Time { return fakeNow }
+	defer func() { timeNow = savedNow }()
+
+	factory := metricstest.NewFactory(0)
+	defer factory.Stop()
+
+	options := Options{
+		CertPath: certPath,
+		KeyPath:  keyPath,
+		Metrics:  factory,
+	}
+	defer func() { require.NoError(t, options.Close()) }()
+
+	_, err = options.Config(zap.NewNop())
+	require.NoError(t, err)
+
+	_, gauges := factory.Snapshot()
+	expectedSeconds := int64(6 * 24 * time.Hour / time.Second)
+	assert.Equal(t, expectedSeconds, gauges["tls.cert_expiry_seconds|role=server"])
+	assert.Equal(t, expectedSeconds, gauges["tls.cert_expiry_seconds|role=client"])
+}
+
+func TestMetricsReloadCounters(t *testing.T) {
+	certDir := t.TempDir()
+	keyPath := filepath.Join(certDir, "cert-key.pem")
+	certPath := filepath.Join(certDir, "cert.pem")
+	copyFile(t, testCertKeyLocation+"/example-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/example-client-cert.pem", certPath)
+
+	factory := metricstest.NewFactory(0)
+	defer factory.Stop()
+
+	options := Options{
+		CertPath:       certPath,
+		KeyPath:        keyPath,
+		ReloadInterval: 10 * time.Millisecond,
+		Metrics:        factory,
+	}
+	defer func() { require.NoError(t, options.Close()) }()
+
+	_, err := options.Config(zap.NewNop())
+	require.NoError(t, err)
+
+	// Reload counters only reflect actual rotations, not polling ticks with
+	// unchanged content, so a tick alone must not move them.
+	time.Sleep(50 * time.Millisecond)
+	counters, _ := factory.Snapshot()
+	assert.Zero(t, counters["tls.reload_total|result=success"], "an unchanged certificate must not be counted as a reload")
+
+	copyFile(t, testCertKeyLocation+"/reload-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/reload-client-cert.pem", certPath)
+
+	require.Eventually(t, func() bool {
+		counters, _ := factory.Snapshot()
+		return counters["tls.reload_total|result=success"] > 0
+	}, time.Second, 10*time.Millisecond, "expected the actual rotation to be recorded")
+}
+
 func TestConcurrentCertPoolAccessForDataRace(t *testing.T) {
 	certPath := filepath.Join(testCertKeyLocation, "example-CA-cert.pem")
 	certBytes, err := os.ReadFile(certPath)
@@ -215,6 +350,134 @@ func TestConcurrentCertPoolAccessForDataRace(t *testing.T) {
 	wg.Wait()
 }
 
+func TestCertificateReload(t *testing.T) {
+	certDir := t.TempDir()
+	keyPath := filepath.Join(certDir, "cert-key.pem")
+	certPath := filepath.Join(certDir, "cert.pem")
+
+	copyFile(t, testCertKeyLocation+"/example-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/example-client-cert.pem", certPath)
+
+	options := Options{
+		CAPath:         testCertKeyLocation + "/example-CA-cert.pem",
+		CertPath:       certPath,
+		KeyPath:        keyPath,
+		ReloadInterval: 10 * time.Millisecond,
+	}
+	defer func() { require.NoError(t, options.Close()) }()
+
+	cfg, err := options.Config(zap.NewNop())
+	require.NoError(t, err)
+
+	originalCert, err := tls.LoadX509KeyPair(testCertKeyLocation+"/example-client-cert.pem", testCertKeyLocation+"/example-client-key.pem")
+	require.NoError(t, err)
+	cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, &originalCert, cert)
+
+	// Swap in a different cert/key pair mid-flight and expect the next
+	// refresh cycle to pick it up.
+	copyFile(t, testCertKeyLocation+"/reload-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/reload-client-cert.pem", certPath)
+
+	reloadedCert, err := tls.LoadX509KeyPair(testCertKeyLocation+"/reload-client-cert.pem", testCertKeyLocation+"/reload-client-key.pem")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+		return err == nil && assert.ObjectsAreEqual(&reloadedCert, cert)
+	}, time.Second, 10*time.Millisecond, "expected reloaded leaf certificate within one refresh cycle")
+}
+
+func TestCertificateReloadFailureKeepsServingLastGood(t *testing.T) {
+	certDir := t.TempDir()
+	keyPath := filepath.Join(certDir, "cert-key.pem")
+	certPath := filepath.Join(certDir, "cert.pem")
+
+	copyFile(t, testCertKeyLocation+"/example-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/example-client-cert.pem", certPath)
+
+	options := Options{
+		CertPath:       certPath,
+		KeyPath:        keyPath,
+		ReloadInterval: 10 * time.Millisecond,
+	}
+	defer func() { require.NoError(t, options.Close()) }()
+
+	cfg, err := options.Config(zap.NewNop())
+	require.NoError(t, err)
+
+	originalCert, err := tls.LoadX509KeyPair(testCertKeyLocation+"/example-client-cert.pem", testCertKeyLocation+"/example-client-key.pem")
+	require.NoError(t, err)
+
+	// Corrupt the cert file; the reloader must log the failure and keep
+	// serving the last-good certificate instead of crashing.
+	require.NoError(t, os.WriteFile(certPath, []byte("not a cert"), 0o600))
+	time.Sleep(50 * time.Millisecond)
+
+	cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, &originalCert, cert)
+}
+
+func TestCertificateReloadOnChange(t *testing.T) {
+	certDir := t.TempDir()
+	keyPath := filepath.Join(certDir, "cert-key.pem")
+	certPath := filepath.Join(certDir, "cert.pem")
+
+	copyFile(t, testCertKeyLocation+"/example-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/example-client-cert.pem", certPath)
+
+	var reloads int32
+	options := Options{
+		CertPath:       certPath,
+		KeyPath:        keyPath,
+		ReloadOnChange: true,
+		OnReload: func(err error) {
+			require.NoError(t, err)
+			atomic.AddInt32(&reloads, 1)
+		},
+	}
+	defer func() { require.NoError(t, options.Close()) }()
+
+	cfg, err := options.Config(zap.NewNop())
+	require.NoError(t, err)
+
+	reloadedCert, err := tls.LoadX509KeyPair(testCertKeyLocation+"/reload-client-cert.pem", testCertKeyLocation+"/reload-client-key.pem")
+	require.NoError(t, err)
+
+	copyFile(t, testCertKeyLocation+"/reload-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/reload-client-cert.pem", certPath)
+
+	require.Eventually(t, func() bool {
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+		return err == nil && assert.ObjectsAreEqual(&reloadedCert, cert)
+	}, time.Second, 10*time.Millisecond, "expected fsnotify-triggered reload to pick up the new leaf certificate")
+	firstReloadCount := atomic.LoadInt32(&reloads)
+	assert.Positive(t, firstReloadCount)
+
+	// A second, later swap must also be picked up: the debounce timer must
+	// be re-armed after firing, not left pointing at a spent/nil channel.
+	originalCert, err := tls.LoadX509KeyPair(testCertKeyLocation+"/example-client-cert.pem", testCertKeyLocation+"/example-client-key.pem")
+	require.NoError(t, err)
+
+	copyFile(t, testCertKeyLocation+"/example-client-key.pem", keyPath)
+	copyFile(t, testCertKeyLocation+"/example-client-cert.pem", certPath)
+
+	require.Eventually(t, func() bool {
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+		return err == nil && assert.ObjectsAreEqual(&originalCert, cert)
+	}, time.Second, 10*time.Millisecond, "expected a second fsnotify-triggered reload to pick up the reverted leaf certificate")
+	assert.Greater(t, atomic.LoadInt32(&reloads), firstReloadCount)
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Clean(src))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, data, 0o600))
+}
+
 func TestConcurrentConfigAccess(t *testing.T) {
 	logger := zap.NewNop()
 	options := Options{
@@ -241,3 +504,21 @@ func TestConcurrentConfigAccess(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestOptionsSourceDefaultsToFile(t *testing.T) {
+	options := Options{
+		CAPath: testCertKeyLocation + "/example-CA-cert.pem",
+	}
+	defer func() { require.NoError(t, options.Close()) }()
+
+	_, err := options.Config(zap.NewNop())
+	require.NoError(t, err)
+}
+
+func TestOptionsUnknownSource(t *testing.T) {
+	options := Options{Source: "quantum-key-distribution"}
+	defer func() { require.NoError(t, options.Close()) }()
+
+	_, err := options.Config(zap.NewNop())
+	require.ErrorContains(t, err, `unknown tls source "quantum-key-distribution"`)
+}
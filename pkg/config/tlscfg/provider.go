@@ -0,0 +1,52 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// CertificateProvider is the source of truth for the TLS material used by
+// Options.Config. The file-based loader that previously lived directly in
+// Config is one implementation (see fileProvider); SPIFFE and Vault-backed
+// implementations live under pkg/config/tlscfg/providers/.
+type CertificateProvider interface {
+	// GetServerCertificate returns the certificate this process presents
+	// to clients; it backs tls.Config.GetCertificate.
+	GetServerCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	// GetClientCertificate returns the certificate this process presents
+	// when acting as a client; it backs tls.Config.GetClientCertificate.
+	// Unlike GetServerCertificate, this must never return a nil
+	// *tls.Certificate: the standard library's client handshake
+	// dereferences the result unconditionally once the server has asked
+	// for a client certificate, even if none is configured, so
+	// implementations return &tls.Certificate{} in that case.
+	GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	// GetRoots returns the trust pool used to verify remote servers.
+	GetRoots() (*x509.CertPool, error)
+	// GetClientCAs returns the trust pool used to verify client
+	// certificates; nil if client cert verification is not configured.
+	GetClientCAs() (*x509.CertPool, error)
+	// Subscribe returns a channel that receives a value when the
+	// provider's underlying certificates or trust pools change, so
+	// callers can refresh metrics or logs. A change that happens while a
+	// value is still pending is not queued separately, and Close does not
+	// close the channel.
+	Subscribe() <-chan struct{}
+	// Close releases any background resources (goroutines, streams,
+	// leases) held by the provider.
+	Close() error
+}
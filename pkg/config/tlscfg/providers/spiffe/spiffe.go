@@ -0,0 +1,211 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spiffe implements a tlscfg.CertificateProvider backed by the
+// SPIFFE Workload API, so that a process can obtain and keep rotating its
+// X.509 SVID and trust bundle from a local SPIFFE agent (e.g. SPIRE)
+// instead of reading certificate material from disk.
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"go.uber.org/zap"
+)
+
+// x509Source is the subset of *workloadapi.X509Source's methods Provider
+// needs. Narrowing to an interface lets tests exercise Provider against a
+// fake Workload API instead of a real SPIFFE agent.
+type x509Source interface {
+	GetX509SVID() (*x509svid.SVID, error)
+	GetX509BundleForTrustDomain(spiffeid.TrustDomain) (*x509bundle.Bundle, error)
+	Close() error
+}
+
+// pollInterval is how often Provider checks whether the Workload API has
+// rotated the SVID, so Subscribe callers can be notified of the change.
+// go-spiffe refreshes source in the background on its own schedule; this
+// only governs how promptly that refresh is observed here.
+const pollInterval = 30 * time.Second
+
+// Options configures Provider.
+type Options struct {
+	// WorkloadAPIAddr is the address of the SPIFFE Workload API, e.g.
+	// "unix:///run/spire/sockets/agent.sock". If empty, go-spiffe's default
+	// (the SPIFFE_ENDPOINT_SOCKET environment variable) is used.
+	WorkloadAPIAddr string
+}
+
+// Provider obtains an X.509 SVID and the trust bundle for its trust domain
+// from the SPIFFE Workload API and keeps them updated for as long as it is
+// open. It implements tlscfg.CertificateProvider.
+type Provider struct {
+	source x509Source
+	logger *zap.Logger
+
+	changeCh chan struct{}
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+
+	mu         sync.RWMutex
+	lastSerial string
+}
+
+// New connects to the SPIFFE Workload API described by opts and blocks
+// until the initial SVID and trust bundle have been fetched.
+func New(ctx context.Context, opts Options, logger *zap.Logger) (*Provider, error) {
+	var clientOpts []workloadapi.ClientOption
+	if opts.WorkloadAPIAddr != "" {
+		clientOpts = append(clientOpts, workloadapi.WithAddr(opts.WorkloadAPIAddr))
+	}
+
+	source, err := workloadapi.NewX509Source(ctx, workloadapi.WithClientOptions(clientOpts...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SPIFFE Workload API: %w", err)
+	}
+
+	p := &Provider{
+		source:   source,
+		logger:   logger,
+		changeCh: make(chan struct{}, 1),
+		closeCh:  make(chan struct{}),
+	}
+
+	if svid, err := source.GetX509SVID(); err == nil {
+		p.lastSerial = serialOf(svid)
+	}
+
+	p.wg.Add(1)
+	go p.pollForRotation()
+
+	return p, nil
+}
+
+// GetServerCertificate returns the current X.509 SVID as the certificate
+// this process presents to clients.
+func (p *Provider) GetServerCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return p.svidCertificate()
+}
+
+// GetClientCertificate returns the current X.509 SVID as the certificate
+// this process presents when connecting as a client.
+func (p *Provider) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return p.svidCertificate()
+}
+
+// GetRoots returns the X.509 trust bundle for this SVID's own trust domain.
+func (p *Provider) GetRoots() (*x509.CertPool, error) {
+	svid, err := p.source.GetX509SVID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SPIFFE X.509 SVID: %w", err)
+	}
+	bundle, err := p.source.GetX509BundleForTrustDomain(svid.ID.TrustDomain())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SPIFFE trust bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range bundle.X509Authorities() {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// GetClientCAs returns the same trust bundle as GetRoots: SPIFFE does not
+// distinguish a separate client-CA bundle, since mTLS peers in a trust
+// domain are verified against the same trust bundle in both directions.
+func (p *Provider) GetClientCAs() (*x509.CertPool, error) {
+	return p.GetRoots()
+}
+
+// Subscribe returns a channel that receives a value whenever the Workload
+// API has rotated the SVID.
+func (p *Provider) Subscribe() <-chan struct{} {
+	return p.changeCh
+}
+
+// Close stops the background rotation poller and closes the underlying
+// Workload API source.
+func (p *Provider) Close() error {
+	select {
+	case <-p.closeCh:
+	default:
+		close(p.closeCh)
+	}
+	p.wg.Wait()
+	return p.source.Close()
+}
+
+func (p *Provider) svidCertificate() (*tls.Certificate, error) {
+	svid, err := p.source.GetX509SVID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SPIFFE X.509 SVID: %w", err)
+	}
+	if len(svid.Certificates) == 0 {
+		return nil, fmt.Errorf("SPIFFE X.509 SVID has no certificates")
+	}
+	raw := make([][]byte, len(svid.Certificates))
+	for i, cert := range svid.Certificates {
+		raw[i] = cert.Raw
+	}
+	return &tls.Certificate{
+		Certificate: raw,
+		PrivateKey:  svid.PrivateKey,
+		Leaf:        svid.Certificates[0],
+	}, nil
+}
+
+func (p *Provider) pollForRotation() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			svid, err := p.source.GetX509SVID()
+			if err != nil {
+				p.logger.Error("failed to poll SPIFFE Workload API for SVID rotation", zap.Error(err))
+				continue
+			}
+			p.mu.Lock()
+			rotated := serialOf(svid) != p.lastSerial
+			p.lastSerial = serialOf(svid)
+			p.mu.Unlock()
+			if rotated {
+				p.logger.Info("SPIFFE X.509 SVID rotated")
+				select {
+				case p.changeCh <- struct{}{}:
+				default:
+				}
+			}
+		case <-p.closeCh:
+			return
+		}
+	}
+}
+
+func serialOf(svid *x509svid.SVID) string {
+	if len(svid.Certificates) == 0 {
+		return ""
+	}
+	return svid.Certificates[0].SerialNumber.String()
+}
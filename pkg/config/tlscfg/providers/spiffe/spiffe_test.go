@@ -0,0 +1,91 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffe
+
+import (
+	"crypto/x509"
+	"math/big"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTrustDomainID = spiffeid.RequireFromString("spiffe://example.org/workload")
+
+type fakeX509Source struct {
+	svid    *x509svid.SVID
+	svidErr error
+}
+
+func (f *fakeX509Source) GetX509SVID() (*x509svid.SVID, error) {
+	return f.svid, f.svidErr
+}
+
+func (*fakeX509Source) GetX509BundleForTrustDomain(spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	return nil, nil
+}
+
+func (*fakeX509Source) Close() error { return nil }
+
+func TestSvidCertificate(t *testing.T) {
+	leaf := &x509.Certificate{SerialNumber: big.NewInt(1)}
+
+	tests := []struct {
+		name        string
+		svid        *x509svid.SVID
+		svidErr     error
+		expectError string
+	}{
+		{
+			name:        "source error",
+			svidErr:     assert.AnError,
+			expectError: "failed to get SPIFFE X.509 SVID",
+		},
+		{
+			name:        "no certificates",
+			svid:        &x509svid.SVID{ID: testTrustDomainID},
+			expectError: "SPIFFE X.509 SVID has no certificates",
+		},
+		{
+			name: "valid svid",
+			svid: &x509svid.SVID{ID: testTrustDomainID, Certificates: []*x509.Certificate{leaf}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &Provider{source: &fakeX509Source{svid: test.svid, svidErr: test.svidErr}}
+			cert, err := p.svidCertificate()
+			if test.expectError != "" {
+				require.ErrorContains(t, err, test.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Same(t, leaf, cert.Leaf)
+			assert.Len(t, cert.Certificate, 1)
+		})
+	}
+}
+
+func TestSerialOf(t *testing.T) {
+	assert.Empty(t, serialOf(&x509svid.SVID{ID: testTrustDomainID}))
+
+	leaf := &x509.Certificate{SerialNumber: big.NewInt(42)}
+	assert.Equal(t, "42", serialOf(&x509svid.SVID{ID: testTrustDomainID, Certificates: []*x509.Certificate{leaf}}))
+}
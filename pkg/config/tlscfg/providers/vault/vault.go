@@ -0,0 +1,270 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vault implements a tlscfg.CertificateProvider backed by
+// HashiCorp Vault's PKI secrets engine: it issues a short-lived leaf
+// certificate on startup and reissues it before it expires, instead of
+// reading certificate material from disk.
+package vault
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"go.uber.org/zap"
+)
+
+// renewBefore is how long before the issued certificate's expiry a
+// reissue is attempted, mirroring the safety margin commonly used for
+// Vault lease renewal.
+const renewBefore = 30 * time.Second
+
+// minRetryBackoff and maxRetryBackoff bound the backoff used when a reissue
+// attempt fails and must be retried before the certificate actually
+// expires.
+const (
+	minRetryBackoff = 1 * time.Second
+	maxRetryBackoff = 30 * time.Second
+)
+
+// Options configures Provider.
+type Options struct {
+	// Addr is the Vault server address, e.g. "https://vault:8200". If
+	// empty, the Vault client's own default resolution (VAULT_ADDR) is
+	// used.
+	Addr string
+	// Token authenticates to Vault. In production this is typically
+	// supplied out of band (e.g. VAULT_TOKEN) rather than in configuration.
+	Token string
+	// PKIMountPath is the mount path of the PKI secrets engine, e.g. "pki".
+	PKIMountPath string
+	// Role is the PKI role used to issue certificates.
+	Role string
+	// CommonName is the CN requested for issued certificates.
+	CommonName string
+	// TTL is the requested certificate lifetime; Vault may cap it to the
+	// role's configured maximum.
+	TTL time.Duration
+}
+
+// vaultLogical is the subset of *vaultapi.Logical's methods Provider needs.
+// Narrowing to an interface lets tests exercise issue() against a fake PKI
+// backend instead of a real Vault server.
+type vaultLogical interface {
+	Write(path string, data map[string]interface{}) (*vaultapi.Secret, error)
+}
+
+// Provider issues a leaf certificate from a Vault PKI secrets engine and
+// reissues it before it expires. It implements tlscfg.CertificateProvider.
+// Vault PKI does not hand out a CA bundle for verifying peers the way a
+// CAPath would, so GetRoots/GetClientCAs return the issuing CA chain
+// returned alongside the leaf by the same issue call.
+type Provider struct {
+	client vaultLogical
+	opts   Options
+	logger *zap.Logger
+
+	changeCh chan struct{}
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	cas  *x509.CertPool
+}
+
+// New creates a Vault client from opts and issues the initial certificate.
+func New(opts Options, logger *zap.Logger) (*Provider, error) {
+	cfg := vaultapi.DefaultConfig()
+	if opts.Addr != "" {
+		cfg.Address = opts.Addr
+	}
+	client, err := vaultapi.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault client: %w", err)
+	}
+	if opts.Token != "" {
+		client.SetToken(opts.Token)
+	}
+
+	p := &Provider{
+		client:   client.Logical(),
+		opts:     opts,
+		logger:   logger,
+		changeCh: make(chan struct{}, 1),
+		closeCh:  make(chan struct{}),
+	}
+
+	notAfter, err := p.issue()
+	if err != nil {
+		return nil, err
+	}
+
+	p.wg.Add(1)
+	go p.renewLoop(notAfter)
+
+	return p, nil
+}
+
+func (p *Provider) GetServerCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cert, nil
+}
+
+func (p *Provider) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return p.cert, nil
+}
+
+func (p *Provider) GetRoots() (*x509.CertPool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cas, nil
+}
+
+func (p *Provider) GetClientCAs() (*x509.CertPool, error) {
+	return p.GetRoots()
+}
+
+// Subscribe returns a channel that receives a value every time a
+// certificate is successfully issued, including the initial one issued
+// by New.
+func (p *Provider) Subscribe() <-chan struct{} {
+	return p.changeCh
+}
+
+// Close stops the background renewal loop.
+func (p *Provider) Close() error {
+	select {
+	case <-p.closeCh:
+	default:
+		close(p.closeCh)
+	}
+	p.wg.Wait()
+	return nil
+}
+
+// issue requests a new certificate from the PKI secrets engine and swaps it
+// into p, returning the new certificate's expiry so the caller can schedule
+// the next renewal.
+func (p *Provider) issue() (time.Time, error) {
+	path := fmt.Sprintf("%s/issue/%s", p.opts.PKIMountPath, p.opts.Role)
+	data := map[string]interface{}{
+		"common_name": p.opts.CommonName,
+	}
+	if p.opts.TTL > 0 {
+		data["ttl"] = p.opts.TTL.String()
+	}
+
+	secret, err := p.client.Write(path, data)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to issue certificate from Vault PKI engine: %w", err)
+	}
+	if secret == nil {
+		return time.Time{}, fmt.Errorf("vault PKI engine returned no secret for issue request")
+	}
+
+	cert, cas, err := parseIssueResponse(secret.Data)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	p.mu.Lock()
+	p.cert = cert
+	p.cas = cas
+	p.mu.Unlock()
+
+	select {
+	case p.changeCh <- struct{}{}:
+	default:
+	}
+
+	return cert.Leaf.NotAfter, nil
+}
+
+func (p *Provider) renewLoop(notAfter time.Time) {
+	defer p.wg.Done()
+	backoff := minRetryBackoff
+	for {
+		wait := time.Until(notAfter) - renewBefore
+		if wait < 0 {
+			wait = 0
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-p.closeCh:
+			timer.Stop()
+			return
+		}
+
+		next, err := p.issue()
+		if err != nil {
+			p.logger.Error("failed to reissue certificate from Vault, keeping previous certificate", zap.Error(err))
+			// The next wait subtracts renewBefore from notAfter, so add it
+			// back to make that wait equal to backoff.
+			notAfter = time.Now().Add(backoff + renewBefore)
+			backoff *= 2
+			if backoff > maxRetryBackoff {
+				backoff = maxRetryBackoff
+			}
+			continue
+		}
+		backoff = minRetryBackoff
+		notAfter = next
+		p.logger.Info("reissued certificate from Vault PKI engine")
+	}
+}
+
+func parseIssueResponse(data map[string]interface{}) (*tls.Certificate, *x509.CertPool, error) {
+	certPEM, _ := data["certificate"].(string)
+	keyPEM, _ := data["private_key"].(string)
+	if certPEM == "" || keyPEM == "" {
+		return nil, nil, fmt.Errorf("vault PKI response missing certificate or private_key")
+	}
+
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate issued by Vault: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse leaf certificate issued by Vault: %w", err)
+	}
+	cert.Leaf = leaf
+
+	pool := x509.NewCertPool()
+	switch v := data["ca_chain"].(type) {
+	case []interface{}:
+		for _, ca := range v {
+			if s, ok := ca.(string); ok {
+				pool.AppendCertsFromPEM([]byte(s))
+			}
+		}
+	}
+	if issuingCA, ok := data["issuing_ca"].(string); ok && issuingCA != "" {
+		pool.AppendCertsFromPEM([]byte(issuingCA))
+	}
+
+	return &cert, pool, nil
+}
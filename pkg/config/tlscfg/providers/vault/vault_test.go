@@ -0,0 +1,159 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"os"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testdataDir = "../../testdata"
+
+func readTestFile(t *testing.T, name string) string {
+	data, err := os.ReadFile(testdataDir + "/" + name)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestParseIssueResponse(t *testing.T) {
+	certPEM := readTestFile(t, "example-client-cert.pem")
+	keyPEM := readTestFile(t, "example-client-key.pem")
+	caPEM := readTestFile(t, "example-CA-cert.pem")
+
+	tests := []struct {
+		name        string
+		data        map[string]interface{}
+		expectError string
+	}{
+		{
+			name: "valid response with ca_chain",
+			data: map[string]interface{}{
+				"certificate": certPEM,
+				"private_key": keyPEM,
+				"ca_chain":    []interface{}{caPEM},
+			},
+		},
+		{
+			name: "valid response with issuing_ca only",
+			data: map[string]interface{}{
+				"certificate": certPEM,
+				"private_key": keyPEM,
+				"issuing_ca":  caPEM,
+			},
+		},
+		{
+			name: "missing certificate",
+			data: map[string]interface{}{
+				"private_key": keyPEM,
+			},
+			expectError: "vault PKI response missing certificate or private_key",
+		},
+		{
+			name: "missing private_key",
+			data: map[string]interface{}{
+				"certificate": certPEM,
+			},
+			expectError: "vault PKI response missing certificate or private_key",
+		},
+		{
+			name: "mismatched cert and key",
+			data: map[string]interface{}{
+				"certificate": certPEM,
+				"private_key": readTestFile(t, "reload-client-key.pem"),
+			},
+			expectError: "failed to parse certificate issued by Vault",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert, cas, err := parseIssueResponse(test.data)
+			if test.expectError != "" {
+				require.ErrorContains(t, err, test.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, cert.Leaf)
+			assert.NotNil(t, cas)
+		})
+	}
+}
+
+type fakeLogical struct {
+	secret *vaultapi.Secret
+	err    error
+}
+
+func (f *fakeLogical) Write(string, map[string]interface{}) (*vaultapi.Secret, error) {
+	return f.secret, f.err
+}
+
+func TestProviderIssue(t *testing.T) {
+	certPEM := readTestFile(t, "example-client-cert.pem")
+	keyPEM := readTestFile(t, "example-client-key.pem")
+
+	tests := []struct {
+		name        string
+		client      vaultLogical
+		expectError string
+	}{
+		{
+			name: "nil secret with nil error",
+			client: &fakeLogical{
+				secret: nil,
+				err:    nil,
+			},
+			expectError: "vault PKI engine returned no secret for issue request",
+		},
+		{
+			name: "successful issue",
+			client: &fakeLogical{
+				secret: &vaultapi.Secret{
+					Data: map[string]interface{}{
+						"certificate": certPEM,
+						"private_key": keyPEM,
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &Provider{
+				client:   test.client,
+				opts:     Options{PKIMountPath: "pki", Role: "test", CommonName: "example.com"},
+				logger:   zap.NewNop(),
+				changeCh: make(chan struct{}, 1),
+				closeCh:  make(chan struct{}),
+			}
+
+			_, err := p.issue()
+			if test.expectError != "" {
+				require.ErrorContains(t, err, test.expectError)
+				return
+			}
+			require.NoError(t, err)
+			cert, err := p.GetServerCertificate(nil)
+			require.NoError(t, err)
+			assert.NotNil(t, cert)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg/providers/spiffe"
+)
+
+// newSPIFFEProvider adapts SPIFFEOptions to the spiffe package's Provider,
+// which already satisfies CertificateProvider.
+func newSPIFFEProvider(opts SPIFFEOptions, logger *zap.Logger) (CertificateProvider, error) {
+	return spiffe.New(context.Background(), spiffe.Options{
+		WorkloadAPIAddr: opts.WorkloadAPIAddr,
+	}, logger)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2021 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// versionMap maps the human-readable TLS version names accepted on the
+// command line to the corresponding constants from the crypto/tls package.
+var versionMap = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// allowedTLSVersion converts a version string such as "1.2" into the
+// corresponding tls.VersionTLSxx constant. An empty string returns
+// defaultVersion unmodified.
+func allowedTLSVersion(version string, defaultVersion uint16) (uint16, error) {
+	if version == "" {
+		return defaultVersion, nil
+	}
+	id, ok := versionMap[version]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", version)
+	}
+	return id, nil
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2023 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg/providers/vault"
+)
+
+// newVaultProvider adapts VaultOptions to the vault package's Provider,
+// which already satisfies CertificateProvider.
+func newVaultProvider(opts VaultOptions, logger *zap.Logger) (CertificateProvider, error) {
+	return vault.New(vault.Options{
+		Addr:         opts.Addr,
+		Token:        opts.Token,
+		PKIMountPath: opts.PKIMountPath,
+		Role:         opts.Role,
+		CommonName:   opts.CommonName,
+		TTL:          opts.TTL,
+	}, logger)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2022 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// watchDebounce coalesces bursts of filesystem events - e.g. a Kubernetes
+// ConfigMap/Secret update, which touches several symlinks in quick
+// succession - into a single reload.
+const watchDebounce = 100 * time.Millisecond
+
+// startFileWatcher watches the directories containing CAPath, ClientCAPath,
+// CertPath and KeyPath for changes and triggers a reload of p, debounced by
+// watchDebounce, whenever an entry in them is created, written, renamed or
+// removed. The parent directory
+// is watched rather than the file itself so that the common Kubernetes
+// pattern of mounting a file as a symlink into a `..data/` directory, which
+// is atomically swapped on update, is picked up even though the watched
+// directory entry is never itself written to.
+//
+// If fsnotify cannot create a watcher (e.g. no inotify), the error is
+// logged and no watcher is started; on a filesystem that does not deliver
+// notifications, the watcher runs but never fires. In both cases only the
+// ReloadInterval polling loop, if set, reloads the files.
+func (p *fileProvider) startFileWatcher(r *certReloader) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		p.logger.Error("failed to start fsnotify watcher, falling back to ReloadInterval only", zap.Error(err))
+		return
+	}
+
+	for _, dir := range watchedDirs(p.opts) {
+		if err := fw.Add(dir); err != nil {
+			p.logger.Error("failed to watch directory for TLS certificate changes", zap.String("dir", dir), zap.Error(err))
+		}
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer fw.Close()
+
+		var debounce *time.Timer
+		var debounceCh <-chan time.Time
+		for {
+			select {
+			case event, ok := <-fw.Events:
+				if !ok {
+					return
+				}
+				if !isRelevantEvent(event) {
+					continue
+				}
+				if debounce == nil {
+					debounce = time.NewTimer(watchDebounce)
+					debounceCh = debounce.C
+				} else {
+					debounce.Reset(watchDebounce)
+				}
+			case <-debounceCh:
+				debounce = nil
+				debounceCh = nil
+				p.opts.notifyReload(p.reload())
+			case err, ok := <-fw.Errors:
+				if !ok {
+					return
+				}
+				p.logger.Error("fsnotify watcher error", zap.Error(err))
+			case <-r.closeCh:
+				return
+			}
+		}
+	}()
+}
+
+func isRelevantEvent(event fsnotify.Event) bool {
+	return event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0
+}
+
+// watchedDirs returns the deduplicated set of parent directories of the
+// files referenced by p.
+func watchedDirs(p *Options) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		dir := filepath.Dir(path)
+		if seen[dir] {
+			return
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	for _, path := range splitPaths(p.CAPath) {
+		add(path)
+	}
+	for _, path := range splitPaths(p.ClientCAPath) {
+		add(path)
+	}
+	add(p.CertPath)
+	add(p.KeyPath)
+	return dirs
+}